@@ -0,0 +1,326 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// Backup benchmarks backup-appliance access patterns: a low object count,
+// each a very large object written as a long sequential multipart stream
+// at a configurable part size. Concurrency controls how many distinct
+// objects are in flight at once; PerObjectConcurrency controls how many
+// parts of a single object are uploaded or read back in parallel. This
+// models steady-state large-block throughput instead of the small-op IOPS
+// the other benchmarks emphasize.
+type Backup struct {
+	Common
+
+	Bucket string
+
+	// NumObjects is the number of large objects to write and, if Restore
+	// is set, read back.
+	NumObjects int
+
+	// Restore runs a full sequential-GET "restore" phase with ranged
+	// reads sized to PartSize after every object has been written.
+	Restore bool
+
+	keysMu sync.Mutex
+	keys   []string
+}
+
+// Prepare is a no-op: the large sequential writes this benchmark measures
+// happen in Start, not while staging.
+func (b *Backup) Prepare(ctx context.Context) error {
+	return nil
+}
+
+// Start writes NumObjects large objects, Concurrency objects at a time,
+// each as a sequential multipart stream of PerObjectConcurrency parallel
+// parts, then, if Restore is set, reads every object back the same way.
+func (b *Backup) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	<-wait
+
+	var ops Operations
+	ops = append(ops, b.writePhase(ctx)...)
+	if b.Restore {
+		ops = append(ops, b.restorePhase(ctx)...)
+	}
+	return ops, nil
+}
+
+// writePhase uploads NumObjects objects, Concurrency at a time, each
+// object's parts read sequentially from its generator.Source but uploaded
+// PerObjectConcurrency at a time, recording one Operation per part so the
+// report can show write-side per-part latency histograms.
+func (b *Backup) writePhase(ctx context.Context) Operations {
+	concurrency := b.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var ops Operations
+	var idx int32
+
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func(thread uint16) {
+			defer wg.Done()
+			cl, done := b.Client()
+			defer done()
+			src := b.Source()
+			for {
+				n := int(atomic.AddInt32(&idx, 1)) - 1
+				if n >= b.NumObjects || ctx.Err() != nil {
+					return
+				}
+				obj := src.Object()
+				key := b.Prefix + obj.Name
+				parts := b.writeObject(ctx, cl, key, obj, thread)
+				mu.Lock()
+				ops = append(ops, parts...)
+				mu.Unlock()
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+	return ops
+}
+
+// writeObject streams obj sequentially into PartSize buffers and uploads
+// up to PerObjectConcurrency of them in parallel via the multipart API,
+// recording one Operation per part. A failed read or part upload aborts
+// the multipart upload rather than leaving it dangling: Cleanup only lists
+// completed objects, so an un-aborted upload would otherwise accumulate on
+// the bucket across every failed run.
+func (b *Backup) writeObject(ctx context.Context, cl *minio.Client, key string, obj *generator.Object, thread uint16) Operations {
+	core := minio.Core{Client: cl}
+	uploadID, err := core.NewMultipartUpload(ctx, b.Bucket, key, b.PutOpts)
+	if err != nil {
+		return Operations{{OpType: "backup-write", File: key, Thread: thread, Start: time.Now(), End: time.Now(), Err: err.Error()}}
+	}
+
+	partSize := b.PartSize
+	if partSize <= 0 {
+		partSize = obj.Size
+	}
+	numParts := int((obj.Size + partSize - 1) / partSize)
+	if numParts < 1 {
+		numParts = 1
+	}
+	perObject := b.PerObjectConcurrency
+	if perObject < 1 {
+		perObject = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		ops      Operations
+		firstErr error
+	)
+	completed := make([]minio.CompletePart, 0, numParts)
+	sem := make(chan struct{}, perObject)
+
+	// Parts must be read off obj.Reader sequentially since it is a single
+	// stream, but each buffered part is then uploaded concurrently.
+	for partNum := 1; partNum <= numParts; partNum++ {
+		length := partSize
+		if remaining := obj.Size - int64(partNum-1)*partSize; remaining < length {
+			length = remaining
+		}
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(obj.Reader, buf); err != nil {
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(partNum int, buf []byte) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			part, err := core.PutObjectPart(ctx, b.Bucket, key, uploadID, partNum, bytes.NewReader(buf), int64(len(buf)), minio.PutObjectPartOptions{})
+			errStr := ""
+			if err != nil {
+				errStr = err.Error()
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			} else {
+				mu.Lock()
+				completed = append(completed, minio.CompletePart{PartNumber: partNum, ETag: part.ETag})
+				mu.Unlock()
+			}
+
+			mu.Lock()
+			ops = append(ops, Operation{OpType: "backup-write-part", File: key, Thread: thread, Start: start, End: time.Now(), Err: errStr, Size: int64(len(buf))})
+			mu.Unlock()
+		}(partNum, buf)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		if err := core.AbortMultipartUpload(ctx, b.Bucket, key, uploadID); err != nil {
+			ops = append(ops, Operation{OpType: "backup-write", File: key, Thread: thread, Start: time.Now(), End: time.Now(), Err: err.Error()})
+		}
+		return ops
+	}
+
+	if _, err := core.CompleteMultipartUpload(ctx, b.Bucket, key, uploadID, completed, minio.PutObjectOptions{}); err != nil {
+		if abortErr := core.AbortMultipartUpload(ctx, b.Bucket, key, uploadID); abortErr != nil {
+			err = fmt.Errorf("%w (abort also failed: %v)", err, abortErr)
+		}
+		ops = append(ops, Operation{OpType: "backup-write", File: key, Thread: thread, Start: time.Now(), End: time.Now(), Err: err.Error()})
+		return ops
+	}
+
+	b.keysMu.Lock()
+	b.keys = append(b.keys, key)
+	b.keysMu.Unlock()
+	return ops
+}
+
+// restorePhase reads every object written by writePhase back, Concurrency
+// objects at a time.
+func (b *Backup) restorePhase(ctx context.Context) Operations {
+	concurrency := b.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var ops Operations
+	var idx int32
+
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func(thread uint16) {
+			defer wg.Done()
+			cl, done := b.Client()
+			defer done()
+			for {
+				n := int(atomic.AddInt32(&idx, 1)) - 1
+				if n >= len(b.keys) || ctx.Err() != nil {
+					return
+				}
+				parts := b.restoreObject(ctx, cl, b.keys[n], thread)
+				mu.Lock()
+				ops = append(ops, parts...)
+				mu.Unlock()
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+	return ops
+}
+
+// restoreObject reads key back as a sequence of PartSize ranged GETs, up
+// to PerObjectConcurrency of them in flight at once, recording one
+// Operation per part.
+func (b *Backup) restoreObject(ctx context.Context, cl *minio.Client, key string, thread uint16) Operations {
+	info, err := cl.StatObject(ctx, b.Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return Operations{{OpType: "restore-part", File: key, Thread: thread, Start: time.Now(), End: time.Now(), Err: err.Error()}}
+	}
+
+	partSize := b.PartSize
+	if partSize <= 0 {
+		partSize = info.Size
+	}
+	perObject := b.PerObjectConcurrency
+	if perObject < 1 {
+		perObject = 1
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var ops Operations
+	sem := make(chan struct{}, perObject)
+
+	for offset := int64(0); offset < info.Size; offset += partSize {
+		if ctx.Err() != nil {
+			break
+		}
+		length := partSize
+		if offset+length > info.Size {
+			length = info.Size - offset
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(offset, length int64) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			opts := minio.GetObjectOptions{}
+			if err := opts.SetRange(offset, offset+length-1); err != nil {
+				mu.Lock()
+				ops = append(ops, Operation{OpType: "restore-part", File: key, Thread: thread, Start: start, End: time.Now(), Err: err.Error()})
+				mu.Unlock()
+				return
+			}
+
+			o, err := cl.GetObject(ctx, b.Bucket, key, opts)
+			errStr := ""
+			var n int64
+			if err != nil {
+				errStr = err.Error()
+			} else {
+				n, err = io.Copy(io.Discard, o)
+				if err != nil {
+					errStr = err.Error()
+				}
+			}
+			mu.Lock()
+			ops = append(ops, Operation{OpType: "restore-part", File: key, Thread: thread, Start: start, End: time.Now(), Err: errStr, Size: n})
+			mu.Unlock()
+		}(offset, length)
+	}
+	wg.Wait()
+	return ops
+}
+
+// Cleanup removes every object written under the benchmark's prefix.
+func (b *Backup) Cleanup(ctx context.Context) {
+	if b.Clear {
+		b.deleteAllInBucket(ctx, b.Bucket, b.Prefix)
+	}
+}
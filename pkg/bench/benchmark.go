@@ -19,7 +19,7 @@ package bench
 
 import (
 	"context"
-	"math"
+	"fmt"
 	"os"
 	"runtime/pprof"
 	"sync"
@@ -57,7 +57,7 @@ type Common struct {
 	ClientMode bool
 	// Clear prefix after benchmark
 	Clear           bool
-	PrepareProgress chan float64
+	PrepareProgress chan Progress
 
 	// Auto termination is set when this is > 0.
 	AutoTermDur   time.Duration
@@ -65,6 +65,53 @@ type Common struct {
 
 	// Default Put options.
 	PutOpts minio.PutObjectOptions
+
+	// Versioned indicates the benchmark targets a versioned bucket and
+	// should address individual object versions rather than latest-only.
+	Versioned bool
+
+	// Retention options applied to objects that request object locking,
+	// analogous to PutOpts. RetentionMode is unset ("") to disable.
+	RetentionMode     minio.RetentionMode
+	RetentionDuration time.Duration
+	LegalHold         bool
+
+	// GovernanceBypass lets Cleanup remove objects locked under
+	// GOVERNANCE retention by sending x-amz-bypass-governance-retention.
+	GovernanceBypass bool
+
+	// PartSize is the multipart part size used by benchmarks that stream
+	// large sequential objects, e.g. Backup.
+	PartSize int64
+
+	// PerObjectConcurrency is the number of parts of a single object
+	// transferred in parallel, as opposed to Concurrency which controls
+	// how many objects are handled in parallel.
+	PerObjectConcurrency int
+
+	// CheckpointPath, when non-empty, persists Cleanup progress (and
+	// Prepare progress for benchmarks implementing Preparer) so an
+	// interrupted run can resume instead of restarting. It and Resume are
+	// plain fields; no command-line flag sets them in this package.
+	CheckpointPath string
+	// Resume loads CheckpointPath, if present, instead of starting over.
+	Resume bool
+
+	// DeleteBatchSize overrides the number of objects buffered per
+	// RemoveObjects call during cleanup. Zero uses a built-in default.
+	DeleteBatchSize int
+	// DeleteShards splits cleanup's keyspace listing into this many
+	// concurrent partitions per prefix. Zero (or 1) disables sharding.
+	DeleteShards int
+}
+
+// Progress reports prepare-phase progress as both an object count and a
+// byte count: object count alone is misleading for workloads with a few
+// very large objects (e.g. Backup), and byte count alone is misleading for
+// workloads with many small ones.
+type Progress struct {
+	Objects int64
+	Bytes   int64
 }
 
 const (
@@ -82,10 +129,28 @@ func (c *Common) GetCommon() *Common {
 
 // deleteAllInBucket will delete all content in a bucket.
 // If no prefixes are specified everything in bucket is deleted.
+//
+// Each prefix is listed and deleted by c.DeleteShards (default 1)
+// concurrent goroutines, each covering its own partition of the keyspace,
+// since a single lister becomes the bottleneck on very large buckets.
+// Objects are buffered in batches of c.DeleteBatchSize (default 100)
+// before being sent to RemoveObjects. When c.CheckpointPath is set, every
+// shard periodically persists the last key it processed so a run
+// interrupted by Ctrl-C or a lost client can resume with c.Resume instead
+// of re-listing from the start.
 func (c *Common) deleteAllInBucket(ctx context.Context, bucket string, prefixes ...string) {
 	if len(prefixes) == 0 {
 		prefixes = []string{""}
 	}
+	batchSize := c.DeleteBatchSize
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	shards := c.DeleteShards
+	if shards <= 0 {
+		shards = 1
+	}
+
 	finished := make(chan struct{})
 	defer close(finished)
 	go func() {
@@ -96,69 +161,155 @@ func (c *Common) deleteAllInBucket(ctx context.Context, bucket string, prefixes
 			return
 		}
 	}()
+
 	var wg sync.WaitGroup
-	wg.Add(len(prefixes))
 	for _, prefix := range prefixes {
-		go func(prefix string) {
-			defer wg.Done()
-
-			doneCh := make(chan struct{})
-			defer close(doneCh)
-			cl, done := c.Client()
-			defer done()
-			remove := make(chan minio.ObjectInfo, 100)
-			errCh := cl.RemoveObjects(ctx, bucket, remove, minio.RemoveObjectsOptions{})
-			defer func() {
-				// Signal we are done
-				close(remove)
-				// Wait for deletes to finish
-				err := <-errCh
-				if err.Err != nil {
-					console.Error(err.Err)
-				}
-			}()
+		bounds := shardBounds(prefix, shards)
+		for shard := 0; shard < shards; shard++ {
+			wg.Add(1)
+			go func(prefix string, shard int, startAfter, endBefore string) {
+				defer wg.Done()
+				c.deleteShard(ctx, bucket, prefix, shard, startAfter, endBefore, batchSize)
+			}(prefix, shard, bounds[shard], bounds[shard+1])
+		}
+	}
+	wg.Wait()
+}
+
+// shardBounds returns shards+1 key boundaries partitioning prefix's keyspace
+// by the byte following prefix, spread evenly over printable ASCII ('!'
+// through '~'), where typical object names fall: shard i covers keys after
+// bounds[i] up to and including bounds[i+1], and shard 0 also covers prefix
+// itself. Bytes below '!' land in the first shard and bytes above '~'
+// (including non-ASCII UTF-8) in the last. Bounds are computed relative to
+// prefix itself (not raw lexicographic position) so a non-empty prefix
+// still splits across every shard instead of every key landing in
+// whichever shard happens to straddle prefix's own leading bytes. The first
+// and last bounds are prefix itself and "" (unbounded) so every key under
+// prefix, including one that sits exactly on a partition boundary, falls in
+// exactly one shard.
+func shardBounds(prefix string, shards int) []string {
+	const lo, hi = '!', '~' + 1
+	bounds := make([]string, shards+1)
+	bounds[0] = prefix
+	for i := 1; i < shards; i++ {
+		bounds[i] = prefix + string([]byte{byte(lo + i*(hi-lo)/shards)})
+	}
+	bounds[shards] = ""
+	return bounds
+}
+
+// deleteShard lists and removes every object in (startAfter, endBefore]
+// under prefix (shard 0 also includes prefix itself), resuming from a saved
+// checkpoint when c.Resume is set.
+func (c *Common) deleteShard(ctx context.Context, bucket, prefix string, shard int, startAfter, endBefore string, batchSize int) {
+	checkpointPath := c.shardCheckpointPath(shard)
+	cursor := startAfter
+	if shard == 0 {
+		// StartAfter is exclusive: list shard 0 from the prefix itself so a
+		// key equal to prefix is not skipped.
+		cursor = ""
+	}
+	var objects, bytes int64
+	if checkpointPath != "" && c.Resume {
+		if st, err := LoadCheckpoint(checkpointPath); err != nil {
+			console.Error(err)
+		} else if st.Cursor != "" {
+			cursor = st.Cursor
+			objects, bytes = st.Objects, st.Bytes
+		}
+	}
+
+	cl, done := c.Client()
+	defer done()
+	remove := make(chan minio.ObjectInfo, batchSize)
+	errCh := cl.RemoveObjects(ctx, bucket, remove, minio.RemoveObjectsOptions{GovernanceBypass: c.GovernanceBypass})
+	defer func() {
+		// Signal we are done
+		close(remove)
+		// Wait for deletes to finish
+		err := <-errCh
+		if err.Err != nil {
+			console.Error(err.Err)
+		}
+	}()
+
+	save := func() {
+		if checkpointPath == "" {
+			return
+		}
+		st := CheckpointState{Bucket: bucket, Prefix: prefix, Cursor: cursor, Objects: objects, Bytes: bytes}
+		if err := SaveCheckpoint(checkpointPath, st); err != nil {
+			console.Error(err)
+		}
+	}
 
-			objects := cl.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithVersions: true})
+	objectsCh := cl.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true, WithVersions: true, StartAfter: cursor})
+	lastKey := cursor
+	for {
+		select {
+		case obj, ok := <-objectsCh:
+			if !ok {
+				save()
+				return
+			}
+			if obj.Err != nil {
+				console.Error(obj.Err)
+				continue
+			}
+			if endBefore != "" && obj.Key > endBefore {
+				save()
+				return
+			}
+		sendNext:
 			for {
 				select {
-				case obj, ok := <-objects:
-					if !ok {
-						return
-					}
-					if obj.Err != nil {
-						console.Error(obj.Err)
-						continue
-					}
-				sendNext:
-					for {
-						select {
-						case remove <- minio.ObjectInfo{
-							Key:       obj.Key,
-							VersionID: obj.VersionID,
-						}:
-							break sendNext
-						case err := <-errCh:
-							console.Error(err)
-						}
-					}
+				case remove <- minio.ObjectInfo{
+					Key:       obj.Key,
+					VersionID: obj.VersionID,
+				}:
+					break sendNext
 				case err := <-errCh:
 					console.Error(err)
 				}
 			}
-		}(prefix)
+			if obj.Key != lastKey {
+				// Versions of one key are listed together; only a key whose
+				// versions have all been sent is safe to resume after.
+				cursor, lastKey = lastKey, obj.Key
+			}
+			objects++
+			bytes += obj.Size
+			if objects%int64(batchSize) == 0 {
+				save()
+			}
+		case err := <-errCh:
+			console.Error(err)
+		}
 	}
-	wg.Wait()
+}
 
+// shardCheckpointPath returns the checkpoint file a given shard should use,
+// or "" if checkpointing is disabled. Sharded runs get one file per shard
+// so concurrent shards of one prefix never clobber each other; the path does
+// not depend on the prefix, so prefixes deleted together share each file.
+func (c *Common) shardCheckpointPath(shard int) string {
+	if c.CheckpointPath == "" {
+		return ""
+	}
+	if c.DeleteShards <= 1 {
+		return c.CheckpointPath
+	}
+	return fmt.Sprintf("%s.shard%d", c.CheckpointPath, shard)
 }
 
-// prepareProgress updates preparation progess with the value 0->1.
-func (c *Common) prepareProgress(progress float64) {
+// prepareProgress reports cumulative objects/bytes processed so far.
+func (c *Common) prepareProgress(objects, bytes int64) {
 	if c.PrepareProgress == nil {
 		return
 	}
-	progress = math.Max(0, math.Min(1, progress))
 	select {
-	case c.PrepareProgress <- progress:
+	case c.PrepareProgress <- Progress{Objects: objects, Bytes: bytes}:
 	default:
 	}
 }
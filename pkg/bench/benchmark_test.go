@@ -0,0 +1,69 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import "testing"
+
+func TestShardBoundsCoversPrefixedKeyspace(t *testing.T) {
+	const prefix = "bench/"
+	bounds := shardBounds(prefix, 4)
+	if len(bounds) != 5 {
+		t.Fatalf("expected 5 bounds for 4 shards, got %d", len(bounds))
+	}
+	if bounds[0] != prefix {
+		t.Fatalf("first bound = %q, want prefix %q", bounds[0], prefix)
+	}
+	if bounds[len(bounds)-1] != "" {
+		t.Fatalf("last bound = %q, want unbounded \"\"", bounds[len(bounds)-1])
+	}
+
+	// Every bound but the last must itself fall inside the prefixed
+	// keyspace, and bounds must be strictly increasing, otherwise some
+	// shard covers zero keys or keys land in the wrong shard.
+	for i := 1; i < len(bounds)-1; i++ {
+		if bounds[i] <= bounds[i-1] {
+			t.Fatalf("bounds[%d]=%q is not greater than bounds[%d]=%q", i, bounds[i], i-1, bounds[i-1])
+		}
+		if len(bounds[i]) <= len(prefix) || bounds[i][:len(prefix)] != prefix {
+			t.Fatalf("bounds[%d]=%q is not under prefix %q", i, bounds[i], prefix)
+		}
+	}
+
+	// A representative spread of keys under the prefix must land across
+	// more than one shard, i.e. sharding actually buys parallelism.
+	keys := []string{"bench/aaa", "bench/mmm", "bench/zzz"}
+	seen := map[int]bool{}
+	for _, key := range keys {
+		for shard := 0; shard < 4; shard++ {
+			start, end := bounds[shard], bounds[shard+1]
+			if (key > start || (shard == 0 && key == start)) && (end == "" || key <= end) {
+				seen[shard] = true
+			}
+		}
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected keys to spread across multiple shards, got %v", seen)
+	}
+}
+
+func TestShardBoundsSingleShard(t *testing.T) {
+	bounds := shardBounds("p/", 1)
+	if len(bounds) != 2 || bounds[0] != "p/" || bounds[1] != "" {
+		t.Fatalf("unexpected bounds for a single shard: %v", bounds)
+	}
+}
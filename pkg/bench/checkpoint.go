@@ -0,0 +1,91 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+)
+
+// Preparer is implemented by benchmarks whose Prepare phase can resume a
+// partially-completed staging run, the same way Common.deleteAllInBucket
+// resumes Cleanup. A benchmark with a large NumKeys/NumObjects-style
+// staging loop should implement this and check Common.CheckpointPath /
+// Common.Resume at the top of Prepare.
+type Preparer interface {
+	Benchmark
+
+	// PrepareState returns the benchmark's current staging progress, for
+	// persisting via SaveCheckpoint as Prepare runs.
+	PrepareState() CheckpointState
+
+	// LoadPrepareState restores prepare-phase bookkeeping to match a
+	// previously persisted checkpoint. In-memory state (e.g. the keys or
+	// version IDs staged so far) does not survive a restart, so
+	// implementations should re-derive it by listing the bucket rather
+	// than trusting the checkpoint alone.
+	LoadPrepareState(ctx context.Context, st CheckpointState) error
+}
+
+// CheckpointState is the minimal state needed to resume an interrupted
+// keyspace walk: which bucket/prefix was being processed, how far the
+// object listing had reached, and how much work was already done.
+//
+// Common.deleteAllInBucket uses this to resume a partially-completed
+// Cleanup; a benchmark implementing Preparer uses it the same way to
+// resume a partially-completed Prepare.
+type CheckpointState struct {
+	Bucket string `json:"bucket"`
+	Prefix string `json:"prefix"`
+
+	// Cursor is the last object key that was fully processed. Resuming
+	// lists with StartAfter set to Cursor.
+	Cursor string `json:"cursor"`
+
+	Objects int64 `json:"objects"`
+	Bytes   int64 `json:"bytes"`
+}
+
+// LoadCheckpoint reads a checkpoint previously written by SaveCheckpoint.
+// A missing file is not an error: it returns the zero CheckpointState so
+// callers without a prior run start from scratch.
+func LoadCheckpoint(path string) (CheckpointState, error) {
+	buf, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return CheckpointState{}, nil
+	}
+	if err != nil {
+		return CheckpointState{}, err
+	}
+	var st CheckpointState
+	if err := json.Unmarshal(buf, &st); err != nil {
+		return CheckpointState{}, err
+	}
+	return st, nil
+}
+
+// SaveCheckpoint persists state to path, overwriting any previous
+// checkpoint for the same bucket/prefix.
+func SaveCheckpoint(path string, st CheckpointState) error {
+	buf, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0o644)
+}
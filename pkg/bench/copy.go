@@ -0,0 +1,145 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Copy benchmarks server-side CopyObject and multipart ComposeObject:
+// bytes never traverse the client, which exercises backend-internal I/O
+// paths (EC read, re-encode) that a normal PUT does not.
+type Copy struct {
+	Common
+
+	// Bucket holds the pre-staged source objects.
+	Bucket string
+	// DstBucket receives copies. Equal to Bucket for a same-bucket copy.
+	DstBucket string
+
+	// ComposeParts is the number of source objects combined into one
+	// destination object via ComposeObject. 1 means a plain CopyObject.
+	ComposeParts int
+
+	// ReplaceMetadata requests new user metadata on the destination
+	// instead of copying the source's metadata unchanged.
+	ReplaceMetadata bool
+
+	srcKeys []string
+}
+
+// Prepare stages ComposeParts source objects (1 if this is a plain copy)
+// that every Start iteration will copy or compose from.
+func (c *Copy) Prepare(ctx context.Context) error {
+	if c.ComposeParts < 1 {
+		c.ComposeParts = 1
+	}
+	cl, done := c.Client()
+	defer done()
+
+	src := c.Source()
+	var bytesSoFar int64
+	for i := 0; i < c.ComposeParts; i++ {
+		obj := src.Object()
+		key := c.Prefix + obj.Name
+		if _, err := cl.PutObject(ctx, c.Bucket, key, obj.Reader, obj.Size, c.PutOpts); err != nil {
+			return fmt.Errorf("copy: staging source %s: %w", key, err)
+		}
+		c.srcKeys = append(c.srcKeys, key)
+		bytesSoFar += obj.Size
+		c.prepareProgress(int64(i+1), bytesSoFar)
+	}
+	return nil
+}
+
+// Start fans out CopyObject or ComposeObject requests at Concurrency.
+func (c *Copy) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var ops Operations
+
+	<-wait
+	wg.Add(c.Concurrency)
+	for i := 0; i < c.Concurrency; i++ {
+		go func(thread uint16) {
+			defer wg.Done()
+			cl, done := c.Client()
+			defer done()
+			for ctx.Err() == nil {
+				op := c.runOp(ctx, cl, thread)
+				mu.Lock()
+				ops = append(ops, op)
+				mu.Unlock()
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+	return ops, nil
+}
+
+func (c *Copy) runOp(ctx context.Context, cl *minio.Client, thread uint16) Operation {
+	start := time.Now()
+	dst := minio.CopyDestOptions{
+		Bucket:          c.DstBucket,
+		Object:          fmt.Sprintf("%scopy-%d-%d", c.Prefix, thread, start.UnixNano()),
+		ReplaceMetadata: c.ReplaceMetadata,
+	}
+
+	var info minio.UploadInfo
+	var err error
+	opType := "copy"
+	if len(c.srcKeys) == 1 {
+		info, err = cl.CopyObject(ctx, dst, minio.CopySrcOptions{Bucket: c.Bucket, Object: c.srcKeys[0]})
+	} else {
+		opType = "compose"
+		srcs := make([]minio.CopySrcOptions, len(c.srcKeys))
+		for i, key := range c.srcKeys {
+			srcs[i] = minio.CopySrcOptions{Bucket: c.Bucket, Object: key}
+		}
+		info, err = cl.ComposeObject(ctx, dst, srcs...)
+	}
+
+	errStr := ""
+	if err != nil {
+		errStr = err.Error()
+	}
+	return Operation{
+		OpType: opType,
+		File:   dst.Object,
+		Thread: thread,
+		Start:  start,
+		End:    time.Now(),
+		Err:    errStr,
+		Size:   info.Size,
+	}
+}
+
+// Cleanup removes staged sources and every destination copy.
+func (c *Copy) Cleanup(ctx context.Context) {
+	if c.Clear {
+		c.deleteAllInBucket(ctx, c.Bucket, c.Prefix)
+		if c.DstBucket != c.Bucket {
+			c.deleteAllInBucket(ctx, c.DstBucket, c.Prefix)
+		}
+	}
+}
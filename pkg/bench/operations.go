@@ -0,0 +1,50 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import "time"
+
+// Operation records the timing and outcome of a single benchmark operation.
+type Operation struct {
+	// OpType identifies the kind of operation, e.g. "PUT", "GET" or a
+	// scenario step name. Benchmarks that only ever perform one kind of
+	// operation may leave this as a constant.
+	OpType string
+
+	// Endpoint is the server that handled the operation, useful when
+	// Common.Client round-robins across several hosts.
+	Endpoint string
+
+	// File is the object key the operation acted on, if applicable.
+	File string
+
+	// Thread is the index of the goroutine that performed the operation.
+	Thread uint16
+
+	Start time.Time
+	End   time.Time
+
+	// Err holds the error string if the operation failed, empty otherwise.
+	Err string
+
+	// Size is the number of bytes transferred by the operation.
+	Size int64
+}
+
+// Operations is a collection of recorded operations from a benchmark run.
+type Operations []Operation
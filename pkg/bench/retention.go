@@ -0,0 +1,171 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/warp/pkg/generator"
+)
+
+// Retention benchmarks WORM-bucket paths: it puts objects under
+// PutObjectRetention and PutObjectLegalHold, then attempts DELETEs (which
+// must fail while the hold is active) and GetObjectRetention reads, so
+// operators can measure the overhead of lock metadata on top of a plain
+// PUT/GET mix.
+type Retention struct {
+	Common
+
+	Bucket string
+
+	keysMu sync.Mutex
+	keys   []string
+}
+
+// Prepare verifies the bucket is versioned, enabling versioning if needed:
+// Object Lock requires a versioned bucket, and PutObjectRetention fails
+// outright without it. Start measures the PUT+retention cost itself, so
+// there is nothing else useful to stage ahead of time.
+func (r *Retention) Prepare(ctx context.Context) error {
+	cl, done := r.Client()
+	defer done()
+
+	cfg, err := cl.GetBucketVersioning(ctx, r.Bucket)
+	if err != nil {
+		return fmt.Errorf("retention: checking bucket versioning: %w", err)
+	}
+	if !cfg.Enabled() {
+		if err := cl.SetBucketVersioning(ctx, r.Bucket, minio.BucketVersioningConfiguration{Status: "Enabled"}); err != nil {
+			return fmt.Errorf("retention: enabling bucket versioning: %w", err)
+		}
+	}
+	return nil
+}
+
+// Start measures the full lock overhead: each iteration PUTs a fresh
+// object under retention/legal-hold, then attempts a DELETE (expected to
+// fail while locked) and a GetObjectRetention read against it, looping for
+// the duration of the run.
+func (r *Retention) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var ops Operations
+
+	<-wait
+	wg.Add(r.Concurrency)
+	for i := 0; i < r.Concurrency; i++ {
+		go func(thread uint16) {
+			defer wg.Done()
+			cl, done := r.Client()
+			defer done()
+			src := r.Source()
+			for ctx.Err() == nil {
+				putOp, key, versionID := r.putWithLock(ctx, cl, src, thread)
+				mu.Lock()
+				ops = append(ops, putOp)
+				mu.Unlock()
+				if putOp.Err != "" {
+					continue
+				}
+
+				mu.Lock()
+				ops = append(ops, r.attemptDelete(ctx, cl, key, versionID, thread))
+				ops = append(ops, r.readRetention(ctx, cl, key, thread))
+				mu.Unlock()
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+	return ops, nil
+}
+
+// putWithLock PUTs a fresh object and applies retention/legal-hold as
+// configured on Common, as one measured "put-retention" operation. It
+// returns the key and version ID the lock was applied to, since a DELETE
+// with no VersionID targets a versioned, locked object by creating a
+// harmless delete marker rather than being denied.
+func (r *Retention) putWithLock(ctx context.Context, cl *minio.Client, src generator.Source, thread uint16) (Operation, string, string) {
+	start := time.Now()
+	obj := src.Object()
+	key := r.Prefix + obj.Name
+
+	info, err := cl.PutObject(ctx, r.Bucket, key, obj.Reader, obj.Size, r.PutOpts)
+	if err == nil && r.RetentionMode != "" {
+		until := time.Now().Add(r.RetentionDuration)
+		mode := r.RetentionMode
+		err = cl.PutObjectRetention(ctx, r.Bucket, key, minio.PutObjectRetentionOptions{
+			Mode:            &mode,
+			RetainUntilDate: &until,
+		})
+	}
+	if err == nil && r.LegalHold {
+		on := minio.LegalHoldEnabled
+		err = cl.PutObjectLegalHold(ctx, r.Bucket, key, minio.PutObjectLegalHoldOptions{Status: &on})
+	}
+
+	errStr := ""
+	if err != nil {
+		errStr = err.Error()
+	} else {
+		r.keysMu.Lock()
+		r.keys = append(r.keys, key)
+		r.keysMu.Unlock()
+	}
+
+	return Operation{OpType: "put-retention", File: key, Thread: thread, Start: start, End: time.Now(), Err: errStr, Size: info.Size}, key, info.VersionID
+}
+
+// attemptDelete targets the specific locked version: unlike a versionless
+// DELETE (which Object Lock never denies — it just creates a delete marker
+// and leaves the locked version untouched), a DELETE against versionID is
+// the operation Object Lock actually rejects while retention/legal-hold is
+// active, so a failure here is the expected, measured outcome.
+func (r *Retention) attemptDelete(ctx context.Context, cl *minio.Client, key, versionID string, thread uint16) Operation {
+	start := time.Now()
+	err := cl.RemoveObject(ctx, r.Bucket, key, minio.RemoveObjectOptions{VersionID: versionID})
+	errStr := ""
+	if err == nil {
+		errStr = "retention: delete of locked object version unexpectedly succeeded"
+	} else {
+		errStr = err.Error()
+	}
+	return Operation{OpType: "delete-denied", File: key, Thread: thread, Start: start, End: time.Now(), Err: errStr}
+}
+
+func (r *Retention) readRetention(ctx context.Context, cl *minio.Client, key string, thread uint16) Operation {
+	start := time.Now()
+	_, _, err := cl.GetObjectRetention(ctx, r.Bucket, key, "")
+	errStr := ""
+	if err != nil {
+		errStr = err.Error()
+	}
+	return Operation{OpType: "get-retention", File: key, Thread: thread, Start: start, End: time.Now(), Err: errStr}
+}
+
+// Cleanup removes staged objects, bypassing governance retention when
+// Common.GovernanceBypass is set; compliance-locked objects will still
+// fail to delete until their retention period expires.
+func (r *Retention) Cleanup(ctx context.Context) {
+	if r.Clear {
+		r.deleteAllInBucket(ctx, r.Bucket, r.Prefix)
+	}
+}
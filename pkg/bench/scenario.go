@@ -0,0 +1,301 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/warp/pkg/generator"
+	"gopkg.in/yaml.v3"
+)
+
+// ScenarioStep describes one stage of a Scenario benchmark run, e.g.
+// "upload N objects at concurrency X" or "run GETs for T seconds". Each step
+// performs a single Op and carries no weight; a mix of operations is not
+// expressible within one step.
+type ScenarioStep struct {
+	// Name identifies the step in reported results and tags every
+	// Operation it produces.
+	Name string `yaml:"name" json:"name"`
+
+	// Op selects the operation the step performs: "put", "get", "stat",
+	// "delete" or "list".
+	Op string `yaml:"op" json:"op"`
+
+	// Concurrency overrides Common.Concurrency for this step. Zero keeps
+	// the parent value.
+	Concurrency int `yaml:"concurrency" json:"concurrency"`
+
+	// Duration runs the step for a fixed wall time. Zero disables the
+	// time limit.
+	Duration time.Duration `yaml:"duration" json:"duration"`
+
+	// Objects bounds the step by operation count instead of (or in
+	// addition to) Duration. Zero disables the limit.
+	Objects int `yaml:"objects" json:"objects"`
+
+	// DeletePercent is only used by "delete" steps: each iteration removes
+	// one random key written by earlier steps with this probability (0-1;
+	// zero or less means every iteration). It does not limit the step to
+	// that fraction of the keys: how many are removed depends on Objects
+	// and Duration, and skipped iterations count toward Objects.
+	DeletePercent float64 `yaml:"deletePercent" json:"deletePercent"`
+}
+
+// ScenarioDefinition is the user-supplied description of a Scenario
+// benchmark: a sequence of steps run in order against the same bucket and
+// prefix.
+type ScenarioDefinition struct {
+	Steps []ScenarioStep `yaml:"steps" json:"steps"`
+}
+
+// LoadScenarioDefinition reads a scenario definition from a YAML or JSON
+// file. YAML is a superset of JSON, so a single decoder handles both.
+func LoadScenarioDefinition(path string) (ScenarioDefinition, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return ScenarioDefinition{}, err
+	}
+	var def ScenarioDefinition
+	if err := yaml.Unmarshal(buf, &def); err != nil {
+		return ScenarioDefinition{}, fmt.Errorf("parsing scenario %s: %w", path, err)
+	}
+	if len(def.Steps) == 0 {
+		return ScenarioDefinition{}, fmt.Errorf("scenario %s defines no steps", path)
+	}
+	return def, nil
+}
+
+// Scenario runs a user-defined sequence of steps, in order, against a single
+// bucket, merging every step's Operations into one combined result tagged
+// by step name. This lets warp reproduce realistic multi-phase traces, such
+// as ingest-then-query or backup/restore, instead of only a single
+// synthetic operation mix.
+type Scenario struct {
+	Common
+
+	Bucket     string
+	Definition ScenarioDefinition
+
+	keysMu sync.Mutex
+	keys   []string
+}
+
+// Prepare does nothing beyond validating that a scenario was loaded; each
+// step stages its own objects as it runs.
+func (s *Scenario) Prepare(ctx context.Context) error {
+	if len(s.Definition.Steps) == 0 {
+		return fmt.Errorf("scenario: no steps defined")
+	}
+	return nil
+}
+
+// Start runs each step in sequence, sharing the accumulated object keys
+// across steps, and returns the merged Operations of every step.
+func (s *Scenario) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	<-wait
+
+	var all Operations
+	for _, step := range s.Definition.Steps {
+		ops, err := s.runStep(ctx, step)
+		all = append(all, ops...)
+		if err != nil {
+			return all, fmt.Errorf("scenario step %q: %w", step.Name, err)
+		}
+	}
+	return all, nil
+}
+
+// Cleanup removes everything written under the scenario's prefix.
+func (s *Scenario) Cleanup(ctx context.Context) {
+	if s.Clear {
+		s.deleteAllInBucket(ctx, s.Bucket, s.Prefix)
+	}
+}
+
+func (s *Scenario) runStep(ctx context.Context, step ScenarioStep) (Operations, error) {
+	concurrency := step.Concurrency
+	if concurrency <= 0 {
+		concurrency = s.Concurrency
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var deadline time.Time
+	if step.Duration > 0 {
+		deadline = time.Now().Add(step.Duration)
+	}
+	var done int32
+
+	var mu sync.Mutex
+	var ops Operations
+
+	var wg sync.WaitGroup
+	wg.Add(concurrency)
+	for i := 0; i < concurrency; i++ {
+		go func(thread uint16) {
+			defer wg.Done()
+			cl, closeFn := s.Client()
+			defer closeFn()
+			src := s.Source()
+			for {
+				if ctx.Err() != nil {
+					return
+				}
+				if !deadline.IsZero() && time.Now().After(deadline) {
+					return
+				}
+				if step.Objects > 0 && int(atomic.AddInt32(&done, 1)) > step.Objects {
+					return
+				}
+				op := s.runOp(ctx, cl, src, step, thread)
+				if op == nil {
+					// e.g. a "delete" step probabilistically skipped this
+					// iteration; don't let a no-op skew the step's stats.
+					continue
+				}
+				mu.Lock()
+				ops = append(ops, *op)
+				mu.Unlock()
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+	return ops, nil
+}
+
+// runOp performs a single operation of the given step and records it. It
+// returns nil if the step chose not to perform an operation this
+// iteration, e.g. a "delete" step skipped by DeletePercent, so callers
+// don't merge a phantom no-op into the step's Operations.
+func (s *Scenario) runOp(ctx context.Context, cl *minio.Client, src generator.Source, step ScenarioStep, thread uint16) *Operation {
+	start := time.Now()
+	var size int64
+	var key, errStr string
+
+	switch step.Op {
+	case "put":
+		obj := src.Object()
+		key = s.Prefix + obj.Name
+		_, err := cl.PutObject(ctx, s.Bucket, key, obj.Reader, obj.Size, s.PutOpts)
+		if err != nil {
+			errStr = err.Error()
+			break
+		}
+		size = obj.Size
+		s.keysMu.Lock()
+		s.keys = append(s.keys, key)
+		s.keysMu.Unlock()
+	case "get":
+		key = s.randomKey()
+		if key == "" {
+			errStr = "scenario: no objects available to get"
+			break
+		}
+		o, err := cl.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+		if err != nil {
+			errStr = err.Error()
+			break
+		}
+		n, err := io.Copy(io.Discard, o)
+		size = n
+		if err != nil {
+			errStr = err.Error()
+		}
+	case "stat":
+		key = s.randomKey()
+		if key == "" {
+			errStr = "scenario: no objects available to stat"
+			break
+		}
+		info, err := cl.StatObject(ctx, s.Bucket, key, minio.StatObjectOptions{})
+		if err != nil {
+			errStr = err.Error()
+			break
+		}
+		size = info.Size
+	case "delete":
+		key = s.randomKeyForRemoval(step.DeletePercent)
+		if key == "" {
+			return nil
+		}
+		if err := cl.RemoveObject(ctx, s.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+			errStr = err.Error()
+		}
+	case "list":
+		for obj := range cl.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{Prefix: s.Prefix, Recursive: true}) {
+			if obj.Err != nil {
+				errStr = obj.Err.Error()
+			}
+		}
+	default:
+		errStr = fmt.Sprintf("scenario: unknown step op %q", step.Op)
+	}
+
+	return &Operation{
+		OpType:   step.Name,
+		Endpoint: cl.EndpointURL().String(),
+		File:     key,
+		Thread:   thread,
+		Start:    start,
+		End:      time.Now(),
+		Err:      errStr,
+		Size:     size,
+	}
+}
+
+// randomKey returns a random previously written key, or "" if none exist.
+func (s *Scenario) randomKey() string {
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	if len(s.keys) == 0 {
+		return ""
+	}
+	return s.keys[rand.Intn(len(s.keys))]
+}
+
+// randomKeyForRemoval returns a random key to delete with the given
+// probability and removes it from the tracked key set, or "" if none was
+// selected or none exist.
+func (s *Scenario) randomKeyForRemoval(probability float64) string {
+	if probability <= 0 {
+		probability = 1
+	}
+	if rand.Float64() > probability {
+		return ""
+	}
+	s.keysMu.Lock()
+	defer s.keysMu.Unlock()
+	if len(s.keys) == 0 {
+		return ""
+	}
+	idx := rand.Intn(len(s.keys))
+	key := s.keys[idx]
+	s.keys[idx] = s.keys[len(s.keys)-1]
+	s.keys = s.keys[:len(s.keys)-1]
+	return key
+}
@@ -0,0 +1,341 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio/pkg/console"
+)
+
+// keyVersions tracks the version IDs written for a single key, in the order
+// they were created.
+type keyVersions struct {
+	key      string
+	versions []string
+}
+
+// Versioned benchmarks a versioned bucket: it PUTs multiple versions per
+// key, then runs a mix of GET-by-versionID, LIST with versions and
+// version-specific DELETE, covering both delete-marker creation and
+// permanent removal.
+type Versioned struct {
+	Common
+
+	Bucket string
+
+	// NumKeys is the number of distinct object keys to create.
+	NumKeys int
+	// VersionsMin/VersionsMax bound how many versions are written per key;
+	// the actual count is chosen uniformly at random in [min, max].
+	VersionsMin int
+	VersionsMax int
+
+	// DeleteMarkerPercent of delete operations create a delete marker
+	// (no VersionID) rather than permanently removing a specific version.
+	DeleteMarkerPercent float64
+
+	objMu sync.Mutex
+	objs  []keyVersions
+
+	// keysDone and bytesDone track staging progress for PrepareState; they
+	// let Prepare resume after the last fully staged key rather than after
+	// individual puts, since the unit of resumable work is a whole key's
+	// versions.
+	keysDone  int
+	bytesDone int64
+}
+
+// Prepare verifies the bucket is versioned, enabling versioning if needed,
+// then writes VersionsMin..VersionsMax versions for each of NumKeys keys.
+// If Common.CheckpointPath and Common.Resume are set, it resumes after
+// whichever key a previous, interrupted run last finished instead of
+// restarting from key 0.
+func (v *Versioned) Prepare(ctx context.Context) error {
+	cl, done := v.Client()
+	defer done()
+
+	cfg, err := cl.GetBucketVersioning(ctx, v.Bucket)
+	if err != nil {
+		return fmt.Errorf("versioned: checking bucket versioning: %w", err)
+	}
+	if !cfg.Enabled() {
+		if err := cl.SetBucketVersioning(ctx, v.Bucket, minio.BucketVersioningConfiguration{Status: "Enabled"}); err != nil {
+			return fmt.Errorf("versioned: enabling bucket versioning: %w", err)
+		}
+	}
+
+	startKey := 0
+	if v.CheckpointPath != "" && v.Resume {
+		st, err := LoadCheckpoint(v.CheckpointPath)
+		if err != nil {
+			return fmt.Errorf("versioned: loading prepare checkpoint: %w", err)
+		}
+		if st.Objects > 0 {
+			if err := v.LoadPrepareState(ctx, st); err != nil {
+				return fmt.Errorf("versioned: resuming prepare: %w", err)
+			}
+			startKey = v.keysDone
+		}
+	}
+
+	src := v.Source()
+	objectsSoFar, bytesSoFar := int64(0), v.bytesDone
+	for _, kv := range v.objs {
+		objectsSoFar += int64(len(kv.versions))
+	}
+	for i := startKey; i < v.NumKeys; i++ {
+		n := v.VersionsMin
+		if v.VersionsMax > v.VersionsMin {
+			n += rand.Intn(v.VersionsMax - v.VersionsMin + 1)
+		}
+		obj := src.Object()
+		key := v.Prefix + obj.Name
+		kv := keyVersions{key: key}
+		for j := 0; j < n; j++ {
+			obj := src.Object()
+			info, err := cl.PutObject(ctx, v.Bucket, key, obj.Reader, obj.Size, v.PutOpts)
+			if err != nil {
+				return fmt.Errorf("versioned: staging %s: %w", key, err)
+			}
+			kv.versions = append(kv.versions, info.VersionID)
+			objectsSoFar++
+			bytesSoFar += obj.Size
+		}
+		v.objs = append(v.objs, kv)
+		v.keysDone = i + 1
+		v.bytesDone = bytesSoFar
+		v.prepareProgress(objectsSoFar, bytesSoFar)
+		if v.keysDone%100 == 0 {
+			v.saveCheckpoint()
+		}
+	}
+	v.saveCheckpoint()
+	return nil
+}
+
+// PrepareState reports staging progress in terms of whole keys completed,
+// for persisting via SaveCheckpoint.
+func (v *Versioned) PrepareState() CheckpointState {
+	return CheckpointState{Bucket: v.Bucket, Prefix: v.Prefix, Objects: int64(v.keysDone), Bytes: v.bytesDone}
+}
+
+// LoadPrepareState restores v.objs by re-listing every version already
+// staged under the prefix, rather than trusting st beyond how many keys to
+// skip: the version IDs PutObject returned in the interrupted run live only
+// in that run's memory, so the bucket itself is the only durable record of
+// which versions exist.
+func (v *Versioned) LoadPrepareState(ctx context.Context, st CheckpointState) error {
+	cl, done := v.Client()
+	defer done()
+
+	byKey := map[string]*keyVersions{}
+	var order []string
+	for obj := range cl.ListObjects(ctx, v.Bucket, minio.ListObjectsOptions{Prefix: v.Prefix, WithVersions: true, Recursive: true}) {
+		if obj.Err != nil {
+			return fmt.Errorf("versioned: listing staged versions: %w", obj.Err)
+		}
+		kv, ok := byKey[obj.Key]
+		if !ok {
+			kv = &keyVersions{key: obj.Key}
+			byKey[obj.Key] = kv
+			order = append(order, obj.Key)
+		}
+		kv.versions = append(kv.versions, obj.VersionID)
+	}
+
+	v.objs = v.objs[:0]
+	for _, key := range order {
+		v.objs = append(v.objs, *byKey[key])
+	}
+	// The re-listed key count is the authoritative resume point; it can
+	// differ from st.Objects if the interrupted run crashed mid-key.
+	v.keysDone = len(v.objs)
+	v.bytesDone = st.Bytes
+	return nil
+}
+
+// saveCheckpoint persists staging progress if Common.CheckpointPath is set.
+func (v *Versioned) saveCheckpoint() {
+	if v.CheckpointPath == "" {
+		return
+	}
+	if err := SaveCheckpoint(v.CheckpointPath, v.PrepareState()); err != nil {
+		console.Error(err)
+	}
+}
+
+// Start runs a mix of version-aware GET, LIST and DELETE operations against
+// the versions staged in Prepare.
+func (v *Versioned) Start(ctx context.Context, wait chan struct{}) (Operations, error) {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var ops Operations
+
+	<-wait
+	wg.Add(v.Concurrency)
+	for i := 0; i < v.Concurrency; i++ {
+		go func(thread uint16) {
+			defer wg.Done()
+			cl, done := v.Client()
+			defer done()
+			for ctx.Err() == nil {
+				op := v.runOp(ctx, cl, thread)
+				mu.Lock()
+				ops = append(ops, op)
+				mu.Unlock()
+			}
+		}(uint16(i))
+	}
+	wg.Wait()
+	return ops, nil
+}
+
+func (v *Versioned) runOp(ctx context.Context, cl *minio.Client, thread uint16) Operation {
+	start := time.Now()
+	opType, key, versionID, errStr := "get", "", "", ""
+	var size int64
+
+	switch rand.Intn(3) {
+	case 0:
+		opType = "get"
+		kv, ver := v.randomVersion()
+		key, versionID = kv, ver
+		if key == "" {
+			errStr = "versioned: no versions available"
+			break
+		}
+		o, err := cl.GetObject(ctx, v.Bucket, key, minio.GetObjectOptions{VersionID: versionID})
+		if err != nil {
+			errStr = err.Error()
+			break
+		}
+		n, err := io.Copy(io.Discard, o)
+		size = n
+		if err != nil {
+			errStr = err.Error()
+		}
+	case 1:
+		opType = "list"
+		for obj := range cl.ListObjects(ctx, v.Bucket, minio.ListObjectsOptions{Prefix: v.Prefix, WithVersions: true, Recursive: true}) {
+			if obj.Err != nil {
+				errStr = obj.Err.Error()
+			}
+		}
+	case 2:
+		if rand.Float64() < v.DeleteMarkerPercent {
+			opType = "delete-marker"
+			key = v.randomKey()
+			if key == "" {
+				errStr = "versioned: no keys available"
+				break
+			}
+			if err := cl.RemoveObject(ctx, v.Bucket, key, minio.RemoveObjectOptions{}); err != nil {
+				errStr = err.Error()
+			}
+		} else {
+			opType = "permanent-delete"
+			key, versionID = v.takeVersion()
+			if key == "" {
+				errStr = "versioned: no versions available"
+				break
+			}
+			if err := cl.RemoveObject(ctx, v.Bucket, key, minio.RemoveObjectOptions{VersionID: versionID}); err != nil {
+				errStr = err.Error()
+			}
+		}
+	}
+
+	return Operation{
+		OpType: opType,
+		File:   key,
+		Thread: thread,
+		Start:  start,
+		End:    time.Now(),
+		Err:    errStr,
+		Size:   size,
+	}
+}
+
+// randomKey returns a random tracked key, or "" if none remain.
+func (v *Versioned) randomKey() string {
+	v.objMu.Lock()
+	defer v.objMu.Unlock()
+	if len(v.objs) == 0 {
+		return ""
+	}
+	return v.objs[rand.Intn(len(v.objs))].key
+}
+
+// randomVersion returns a random tracked (key, versionID) pair, or ("", "")
+// if none remain.
+func (v *Versioned) randomVersion() (string, string) {
+	v.objMu.Lock()
+	defer v.objMu.Unlock()
+	candidates := v.objs[:0:0]
+	for _, kv := range v.objs {
+		if len(kv.versions) > 0 {
+			candidates = append(candidates, kv)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ""
+	}
+	kv := candidates[rand.Intn(len(candidates))]
+	return kv.key, kv.versions[rand.Intn(len(kv.versions))]
+}
+
+// takeVersion removes and returns a random tracked (key, versionID) pair so
+// it is not selected again, or ("", "") if none remain. It picks a random
+// candidate key first, the same way randomVersion does, so permanent-delete
+// ages versions across the whole key population instead of draining
+// whichever key happens to sort first.
+func (v *Versioned) takeVersion() (string, string) {
+	v.objMu.Lock()
+	defer v.objMu.Unlock()
+	var candidates []int
+	for i, kv := range v.objs {
+		if len(kv.versions) > 0 {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", ""
+	}
+	i := candidates[rand.Intn(len(candidates))]
+	kv := v.objs[i]
+	idx := rand.Intn(len(kv.versions))
+	versionID := kv.versions[idx]
+	kv.versions[idx] = kv.versions[len(kv.versions)-1]
+	v.objs[i].versions = kv.versions[:len(kv.versions)-1]
+	return kv.key, versionID
+}
+
+// Cleanup removes everything written under the benchmark's prefix,
+// including all versions and delete markers.
+func (v *Versioned) Cleanup(ctx context.Context) {
+	if v.Clear {
+		v.deleteAllInBucket(ctx, v.Bucket, v.Prefix)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Warp (C) 2019-2020 MinIO, Inc.
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bench
+
+import "testing"
+
+func newTestVersioned(keys, versionsPerKey int) *Versioned {
+	v := &Versioned{}
+	for i := 0; i < keys; i++ {
+		kv := keyVersions{key: string(rune('a' + i))}
+		for j := 0; j < versionsPerKey; j++ {
+			kv.versions = append(kv.versions, string(rune('0'+j)))
+		}
+		v.objs = append(v.objs, kv)
+	}
+	return v
+}
+
+// TestTakeVersionSpreadsAcrossKeys guards against takeVersion draining the
+// first key with remaining versions before moving on to the rest: it should
+// pick a random key each call, like randomVersion does.
+func TestTakeVersionSpreadsAcrossKeys(t *testing.T) {
+	const keys, versionsPerKey = 5, 20
+	v := newTestVersioned(keys, versionsPerKey)
+
+	seen := map[string]bool{}
+	// Stop well short of draining every version so a "first key with
+	// remaining versions" bug would still show only one key visited.
+	for i := 0; i < keys*3; i++ {
+		key, versionID := v.takeVersion()
+		if key == "" {
+			t.Fatalf("takeVersion returned no candidate after %d calls", i)
+		}
+		if versionID == "" {
+			t.Fatalf("takeVersion returned key %q with empty versionID", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("takeVersion only ever returned key(s) %v across %d calls, want spread across multiple keys", seen, keys*3)
+	}
+}
+
+// TestTakeVersionExhaustsAllVersions checks every version is eventually
+// returned exactly once and no key is revisited after it runs dry.
+func TestTakeVersionExhaustsAllVersions(t *testing.T) {
+	const keys, versionsPerKey = 4, 3
+	v := newTestVersioned(keys, versionsPerKey)
+
+	got := map[string]int{}
+	for i := 0; i < keys*versionsPerKey; i++ {
+		key, versionID := v.takeVersion()
+		if key == "" || versionID == "" {
+			t.Fatalf("takeVersion() = (%q, %q) before keyspace was exhausted (call %d)", key, versionID, i)
+		}
+		got[key]++
+	}
+	if key, _ := v.takeVersion(); key != "" {
+		t.Fatalf("takeVersion returned %q after all versions were taken", key)
+	}
+	for key, count := range got {
+		if count != versionsPerKey {
+			t.Fatalf("key %q yielded %d versions, want %d", key, count, versionsPerKey)
+		}
+	}
+}
+
+func TestRandomVersionSkipsDrainedKeys(t *testing.T) {
+	v := newTestVersioned(2, 1)
+	// Drain all versions from the first key only.
+	v.objs[0].versions = nil
+
+	for i := 0; i < 10; i++ {
+		key, versionID := v.randomVersion()
+		if key != v.objs[1].key {
+			t.Fatalf("randomVersion returned %q, want only candidate %q", key, v.objs[1].key)
+		}
+		if versionID == "" {
+			t.Fatalf("randomVersion returned empty versionID for key %q", key)
+		}
+	}
+}